@@ -0,0 +1,69 @@
+// RTLAMR - An rtl-sdr receiver for smart meters operating in the 900MHz ISM band.
+// Copyright (C) 2015 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package decode
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func benchmarkConfig() (cfg PacketConfig) {
+	cfg.DataRate = 32768
+	cfg.BlockSize = 1 << 12
+	cfg.SymbolLength = 8
+	cfg.SymbolLength2 = 16
+	cfg.PreambleSymbols = 16
+	cfg.PacketSymbols = 96
+	cfg.Preamble = "1010101010101010"
+
+	cfg.BlockSize2 = cfg.BlockSize
+	cfg.PreambleLength = cfg.PreambleSymbols * cfg.SymbolLength2
+	cfg.PacketLength = cfg.SymbolLength2 * cfg.PacketSymbols
+	cfg.BufferLength = cfg.PacketLength + cfg.BlockSize
+	return
+}
+
+func benchmarkDecode(b *testing.B, fastMag bool) {
+	cfg := benchmarkConfig()
+	d := NewDecoder(cfg, fastMag)
+
+	rng := rand.New(rand.NewSource(0))
+	block := make([]byte, cfg.BlockSize<<1)
+	for i := range block {
+		block[i] = byte(rng.Intn(256))
+	}
+
+	b.SetBytes(int64(len(block)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d.Decode(block)
+	}
+}
+
+// BenchmarkDecode exercises the full demodulate -> filter -> quantize ->
+// search pipeline on r820t-sized sample blocks, so changes to the magnitude
+// or filter kernels show up as an end-to-end number rather than only in
+// per-function benchmarks.
+func BenchmarkDecode(b *testing.B) {
+	benchmarkDecode(b, false)
+}
+
+// BenchmarkDecodeFastMag is the same pipeline with the alpha-max-beta-min
+// magnitude approximation selected, matching the -fastmag flag.
+func BenchmarkDecodeFastMag(b *testing.B) {
+	benchmarkDecode(b, true)
+}
@@ -0,0 +1,71 @@
+// RTLAMR - An rtl-sdr receiver for smart meters operating in the 900MHz ISM band.
+// Copyright (C) 2015 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package decode
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func correlateTestConfig() PacketConfig {
+	cfg := benchmarkConfig()
+	cfg.DetectorMode = SoftDetector
+	cfg.Threshold = 0.5
+	return cfg
+}
+
+// TestCorrelateRejectsNoise guards against the normalized score being
+// unbounded: without dividing by the template's own norm, the correlator
+// fired on pure noise roughly once every 40 samples.
+func TestCorrelateRejectsNoise(t *testing.T) {
+	cfg := correlateTestConfig()
+	d := NewDecoder(cfg, false)
+
+	rng := rand.New(rand.NewSource(1))
+	for i := range d.Filtered {
+		d.Filtered[i] = rng.NormFloat64()
+	}
+
+	idxs := d.Correlate(d.Filtered[:cfg.BlockSize2])
+	if len(idxs) != 0 {
+		t.Fatalf("got %d false positives on pure noise, want 0: %v", len(idxs), idxs)
+	}
+}
+
+// TestCorrelateDetectsPreamble injects the bipolar preamble template into
+// low-amplitude noise and checks Correlate reports a detection near the
+// injection point.
+func TestCorrelateDetectsPreamble(t *testing.T) {
+	cfg := correlateTestConfig()
+	d := NewDecoder(cfg, false)
+
+	rng := rand.New(rand.NewSource(1))
+	for i := range d.Filtered {
+		d.Filtered[i] = 0.1 * rng.NormFloat64()
+	}
+
+	const injectAt = 512
+	copy(d.Filtered[injectAt:], d.template)
+
+	idxs := d.Correlate(d.Filtered[:cfg.BlockSize2])
+	for _, idx := range idxs {
+		if idx >= injectAt-cfg.SymbolLength2 && idx <= injectAt+cfg.SymbolLength2 {
+			return
+		}
+	}
+	t.Fatalf("injected preamble at %d not detected; got indexes %v", injectAt, idxs)
+}
@@ -23,6 +23,32 @@ import (
 	"math"
 )
 
+// DetectorMode selects the algorithm used to locate the preamble within a
+// block of samples.
+type DetectorMode int
+
+const (
+	// HardDetector searches for an exact match of the preamble against the
+	// quantized bit-decision stream.
+	HardDetector DetectorMode = iota
+	// SoftDetector correlates the un-quantized matched-filter output against
+	// a bipolar preamble template. It trades a bit of CPU for several dB of
+	// sensitivity over HardDetector and naturally suppresses the duplicate
+	// detections HardDetector produces around a true preamble.
+	SoftDetector
+)
+
+func (m DetectorMode) String() string {
+	switch m {
+	case HardDetector:
+		return "hard"
+	case SoftDetector:
+		return "soft"
+	default:
+		return "unknown"
+	}
+}
+
 // PacketConfig specifies packet-specific radio configuration.
 type PacketConfig struct {
 	DataRate                    int
@@ -35,6 +61,25 @@ type PacketConfig struct {
 	BufferLength                   int
 	Preamble                       string
 
+	// DetectorMode selects between hard-decision and soft-decision preamble
+	// search. Zero-value is HardDetector, preserving prior behavior.
+	DetectorMode DetectorMode
+	// Threshold is the minimum amount by which the normalized correlation
+	// must exceed the locally estimated noise floor for SoftDetector to
+	// report a detection. Unused by HardDetector.
+	Threshold float64
+
+	// FilterType selects the matched filter kernel Decoder.Decode uses to
+	// turn demodulated samples into a bit-decision signal. Zero-value is
+	// ManchesterFilterType, preserving prior behavior.
+	FilterType FilterType
+	// FIRTaps supplies the coefficients for FilterType == FIRFilterType and
+	// is unused otherwise.
+	FIRTaps []float64
+	// RaisedCosineBeta is the roll-off factor for FilterType ==
+	// RaisedCosineFilterType and is unused otherwise.
+	RaisedCosineBeta float64
+
 	CenterFreq uint32
 }
 
@@ -49,6 +94,14 @@ func (cfg PacketConfig) Log() {
 	log.Println("PacketSymbols:", cfg.PacketSymbols)
 	log.Println("PacketLength:", cfg.PacketLength)
 	log.Println("Preamble:", cfg.Preamble)
+	log.Println("DetectorMode:", cfg.DetectorMode)
+	if cfg.DetectorMode == SoftDetector {
+		log.Println("Threshold:", cfg.Threshold)
+	}
+	log.Println("FilterType:", cfg.FilterType)
+	if cfg.FilterType == RaisedCosineFilterType {
+		log.Println("RaisedCosineBeta:", cfg.RaisedCosineBeta)
+	}
 }
 
 // Decoder contains buffers and radio configuration.
@@ -60,12 +113,20 @@ type Decoder struct {
 	Filtered  []float64
 	Quantized []byte
 
-	csum  []float64
-	demod Demodulator
+	filter Filter
+	demod  Demodulator
 
 	preamble []byte
 	slices   [][]byte
 
+	// csumSq and template are only used by SoftDetector: csumSq is a rolling
+	// sum-of-squares, built the way ManchesterFilter builds its csum, so
+	// windowed signal energy can be computed in O(1) per shift, and template
+	// is the bipolar (+1/-1)
+	// expansion of Cfg.Preamble at sample resolution.
+	csumSq   []float64
+	template []float64
+
 	pkt []byte
 }
 
@@ -79,7 +140,15 @@ func NewDecoder(cfg PacketConfig, fastMag bool) (d Decoder) {
 	d.Filtered = make([]float64, d.Cfg.BufferLength)
 	d.Quantized = make([]byte, d.Cfg.BufferLength)
 
-	d.csum = make([]float64, d.Cfg.BlockSize+d.Cfg.SymbolLength2+1)
+	// Select the matched filter kernel specified by Cfg.FilterType.
+	switch d.Cfg.FilterType {
+	case RaisedCosineFilterType:
+		d.filter = NewRaisedCosineFilter(d.Cfg, d.Cfg.RaisedCosineBeta)
+	case FIRFilterType:
+		d.filter = NewFIRFilter(d.Cfg.FIRTaps)
+	default:
+		d.filter = NewManchesterFilter(d.Cfg)
+	}
 
 	// Calculate magnitude lookup table specified by -fastmag flag.
 	if fastMag {
@@ -96,6 +165,23 @@ func NewDecoder(cfg PacketConfig, fastMag bool) (d Decoder) {
 		}
 	}
 
+	// SoftDetector correlates against the preamble expanded to sample
+	// resolution rather than searching the quantized bit stream.
+	if d.Cfg.DetectorMode == SoftDetector {
+		d.csumSq = make([]float64, d.Cfg.BlockSize+d.Cfg.SymbolLength2+1)
+
+		d.template = make([]float64, len(d.Cfg.Preamble)*d.Cfg.SymbolLength2)
+		for symbolIdx := range d.Cfg.Preamble {
+			bit := -1.0
+			if d.Cfg.Preamble[symbolIdx] == '1' {
+				bit = 1.0
+			}
+			for sampleIdx := 0; sampleIdx < d.Cfg.SymbolLength2; sampleIdx++ {
+				d.template[symbolIdx*d.Cfg.SymbolLength2+sampleIdx] = bit
+			}
+		}
+	}
+
 	// Slice quantized sample buffer to make searching for the preamble more
 	// memory local. Pre-allocate a flat buffer so memory is contiguous and
 	// assign slices to the buffer.
@@ -135,7 +221,7 @@ func (d Decoder) Decode(input []byte) []int {
 	filterBlock := d.Filtered[d.Cfg.PacketLength-d.Cfg.SymbolLength2:]
 
 	// Perform matched filter on new block.
-	d.Filter(signalBlock, filterBlock)
+	d.filter.Execute(signalBlock, filterBlock)
 
 	// Perform bit-decision on new block.
 	Quantize(filterBlock, d.Quantized[d.Cfg.PacketLength-d.Cfg.SymbolLength2:])
@@ -143,6 +229,12 @@ func (d Decoder) Decode(input []byte) []int {
 	// Pack the quantized signal into slices for searching.
 	d.Pack(d.Quantized[:d.Cfg.BlockSize2], d.slices)
 
+	if d.Cfg.DetectorMode == SoftDetector {
+		// Correlate against the matched-filter output directly, ahead of
+		// quantization, for better sensitivity on weak signals.
+		return d.Correlate(d.Filtered[:d.Cfg.BlockSize2])
+	}
+
 	// Return a list of indexes the preamble exists at.
 	return d.Search(d.slices, d.preamble)
 }
@@ -167,7 +259,17 @@ func NewSqrtMagLUT() (lut MagLUT) {
 }
 
 // Calculates complex magnitude on given IQ stream writing result to output.
+// Dispatches through magLUTExecute so architectures with a vectorized
+// implementation (see demod_amd64.go) can swap it in at init time.
 func (lut MagLUT) Execute(input []byte, output []float64) {
+	magLUTExecute(lut, input, output)
+}
+
+// magLUTExecute is the active MagLUT implementation. It defaults to the
+// portable Go version and may be overridden in an arch-specific init().
+var magLUTExecute = magLUTExecuteGo
+
+func magLUTExecuteGo(lut MagLUT, input []byte, output []float64) {
 	for idx := 0; idx < len(input); idx += 2 {
 		output[idx>>1] = math.Sqrt(lut[input[idx]] + lut[input[idx+1]])
 	}
@@ -186,7 +288,18 @@ func NewAlphaMaxBetaMinLUT() (lut AlphaMaxBetaMinLUT) {
 }
 
 // Calculates complex magnitude on given IQ stream writing result to output.
+// Dispatches through ambmLUTExecute so architectures with a vectorized
+// implementation (see demod_amd64.go) can swap it in at init time.
 func (lut AlphaMaxBetaMinLUT) Execute(input []byte, output []float64) {
+	ambmLUTExecute(lut, input, output)
+}
+
+// ambmLUTExecute is the active AlphaMaxBetaMinLUT implementation. It
+// defaults to the portable Go version and may be overridden in an
+// arch-specific init().
+var ambmLUTExecute = ambmLUTExecuteGo
+
+func ambmLUTExecuteGo(lut AlphaMaxBetaMinLUT, input []byte, output []float64) {
 	const (
 		α = 0.948059448969
 		ß = 0.392699081699
@@ -203,25 +316,29 @@ func (lut AlphaMaxBetaMinLUT) Execute(input []byte, output []float64) {
 	}
 }
 
-// Matched filter for Manchester coded signals. Output signal's sign at each
-// sample determines the bit-value since Manchester symbols have odd symmetry.
-func (d Decoder) Filter(input, output []float64) {
-	// Computing the cumulative summation over the signal simplifies
-	// filtering to the difference of a pair of subtractions.
-	var sum float64
-	for idx, v := range input {
-		sum += v
-		d.csum[idx+1] = sum
-	}
+// defaultSqrtMagLUT and defaultAlphaMaxBetaMinLUT are the tables
+// NewSqrtMagLUT and NewAlphaMaxBetaMinLUT build. The vectorized kernels in
+// demod_amd64.go and demod_arm64.go bake in the same DC-offset and
+// alpha/beta constants as asm immediates rather than reading them out of
+// the lut argument they're threaded through, so sameLUT lets them verify a
+// caller's lut still matches one of these before dispatching to the
+// kernel, falling back to the portable path otherwise. This keeps a future
+// tuning of either table from silently diverging between the two.
+var (
+	defaultSqrtMagLUT         = NewSqrtMagLUT()
+	defaultAlphaMaxBetaMinLUT = NewAlphaMaxBetaMinLUT()
+)
 
-	// Filter result is difference of summation of lower and upper symbols.
-	lower := d.csum[d.Cfg.SymbolLength:]
-	upper := d.csum[d.Cfg.SymbolLength2:]
-	for idx := range input[:len(input)-d.Cfg.SymbolLength2] {
-		output[idx] = (lower[idx] - d.csum[idx]) - (upper[idx] - lower[idx])
+func sameLUT(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
 	}
-
-	return
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return false
+		}
+	}
+	return true
 }
 
 // Bit-value is determined by the sign of each sample after filtering.
@@ -262,6 +379,86 @@ func (d Decoder) Search(slices [][]byte, preamble []byte) (indexes []int) {
 	return
 }
 
+// Correlate computes the sliding normalized cross-correlation of the
+// matched-filter output against the bipolar preamble template and returns
+// the sample offsets of local maxima whose correlation exceeds a locally
+// estimated noise floor by Cfg.Threshold. Indexes are absolute in the
+// unsliced filtered buffer.
+func (d Decoder) Correlate(input []float64) (indexes []int) {
+	tmplLen := len(d.template)
+
+	// Maintain a rolling sum-of-squares of the signal so windowed energy can
+	// be computed in O(1) per shift, the same trick ManchesterFilter uses for
+	// its cumulative sum.
+	var sumSq float64
+	for idx, v := range input {
+		sumSq += v * v
+		d.csumSq[idx+1] = sumSq
+	}
+
+	scores := make([]float64, len(input)-tmplLen)
+	for idx := range scores {
+		var dot float64
+		for t, tv := range d.template {
+			dot += input[idx+t] * tv
+		}
+
+		energy := d.csumSq[idx+tmplLen] - d.csumSq[idx]
+		if energy <= 0 {
+			continue
+		}
+
+		// Normalize by both the signal energy and the template's own norm
+		// (tmplLen, since the bipolar template's entries are all ±1) so
+		// scores are a proper correlation coefficient bounded in [-1, 1]
+		// rather than scaling with signal energy alone.
+		scores[idx] = dot / math.Sqrt(energy*float64(tmplLen))
+	}
+
+	// Estimate a trailing noise floor from the window preceding idx
+	// (excluding idx itself, so a genuine peak can't raise its own
+	// threshold) and flag offsets whose correlation clears it by
+	// Cfg.Threshold.
+	noiseWindow := d.Cfg.SymbolLength2
+	var noiseSum float64
+	for idx, score := range scores {
+		count := idx
+		if count > noiseWindow {
+			count = noiseWindow
+		}
+		var floor float64
+		if count > 0 {
+			floor = noiseSum / float64(count)
+		}
+
+		if score >= floor+d.Cfg.Threshold {
+			// Only report local maxima within a symbol-length window so a
+			// single preamble doesn't produce the run of duplicate
+			// detections Slice otherwise has to dedup via its seen map.
+			isPeak := true
+			for w := idx - d.Cfg.SymbolLength2; w <= idx+d.Cfg.SymbolLength2; w++ {
+				if w < 0 || w >= len(scores) || w == idx {
+					continue
+				}
+				if scores[w] > score {
+					isPeak = false
+					break
+				}
+			}
+			if isPeak {
+				indexes = append(indexes, idx)
+			}
+		}
+
+		noiseSum += math.Abs(score)
+		if idx >= noiseWindow {
+			noiseSum -= math.Abs(scores[idx-noiseWindow])
+		}
+	}
+
+	return
+}
+
 // Given a list of indeces the preamble exists at, sample the appropriate bits
 // of the signal's bit-decision. Pack bits of each index into an array of byte
 // arrays and return.
@@ -0,0 +1,77 @@
+// RTLAMR - An rtl-sdr receiver for smart meters operating in the 900MHz ISM band.
+// Copyright (C) 2015 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build arm64 && gc && !purego
+
+package decode
+
+func init() {
+	magLUTExecute = magLUTExecuteNEON
+	ambmLUTExecute = ambmLUTExecuteNEON
+}
+
+// sqrtMagNEON and alphaMaxBetaMinNEON are implemented in demod_arm64.s and
+// hardcode the DC-offset and alpha/beta constants NewSqrtMagLUT and
+// NewAlphaMaxBetaMinLUT use as asm immediates, rather than reading them out
+// of a lut argument. Each processes whole groups of 16 input bytes (8 IQ
+// pairs); callers must only pass a byte count that's a multiple of 16 and
+// size output accordingly.
+//
+//go:noescape
+func sqrtMagNEON(input []byte, output []float64)
+
+//go:noescape
+func alphaMaxBetaMinNEON(input []byte, output []float64)
+
+func magLUTExecuteNEON(lut MagLUT, input []byte, output []float64) {
+	if !sameLUT(lut, defaultSqrtMagLUT) {
+		magLUTExecuteGo(lut, input, output)
+		return
+	}
+
+	groups := len(input) / 16
+	if groups == 0 {
+		magLUTExecuteGo(lut, input, output)
+		return
+	}
+
+	n := groups * 16
+	sqrtMagNEON(input[:n], output[:groups*8])
+
+	if rem := input[n:]; len(rem) > 0 {
+		magLUTExecuteGo(lut, rem, output[groups*8:])
+	}
+}
+
+func ambmLUTExecuteNEON(lut AlphaMaxBetaMinLUT, input []byte, output []float64) {
+	if !sameLUT(lut, defaultAlphaMaxBetaMinLUT) {
+		ambmLUTExecuteGo(lut, input, output)
+		return
+	}
+
+	groups := len(input) / 16
+	if groups == 0 {
+		ambmLUTExecuteGo(lut, input, output)
+		return
+	}
+
+	n := groups * 16
+	alphaMaxBetaMinNEON(input[:n], output[:groups*8])
+
+	if rem := input[n:]; len(rem) > 0 {
+		ambmLUTExecuteGo(lut, rem, output[groups*8:])
+	}
+}
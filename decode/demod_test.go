@@ -0,0 +1,105 @@
+// RTLAMR - An rtl-sdr receiver for smart meters operating in the 900MHz ISM band.
+// Copyright (C) 2015 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package decode
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// TestMagLUTExecuteParity checks the dispatched MagLUT.Execute (AVX2/NEON on
+// architectures that have a kernel, scalar Go elsewhere) against
+// magLUTExecuteGo as an oracle. Plain `go test ./...` never runs
+// BenchmarkDecode, the only thing that previously reached the vectorized
+// kernels, and benchmarks don't assert correctness, so this is the only
+// thing that would catch a mis-encoded shuffle mask or constant in the asm.
+func TestMagLUTExecuteParity(t *testing.T) {
+	lut := NewSqrtMagLUT()
+	rng := rand.New(rand.NewSource(1))
+
+	// Sizes spanning several whole groups of 16 bytes plus a remainder, so
+	// both the vectorized path and its scalar tail are exercised.
+	for _, n := range []int{0, 2, 16, 30, 160, 162, 4096 + 6} {
+		input := make([]byte, n)
+		rng.Read(input)
+
+		want := make([]float64, n/2)
+		magLUTExecuteGo(lut, input, want)
+
+		got := make([]float64, n/2)
+		lut.Execute(input, got)
+
+		for idx := range want {
+			if math.Abs(got[idx]-want[idx]) > 1e-3 {
+				t.Fatalf("n=%d idx=%d: got %v, want %v", n, idx, got[idx], want[idx])
+			}
+		}
+	}
+}
+
+// TestAlphaMaxBetaMinLUTExecuteParity is TestMagLUTExecuteParity's
+// counterpart for the alpha-max-beta-min approximation selected by
+// -fastmag.
+func TestAlphaMaxBetaMinLUTExecuteParity(t *testing.T) {
+	lut := NewAlphaMaxBetaMinLUT()
+	rng := rand.New(rand.NewSource(1))
+
+	for _, n := range []int{0, 2, 16, 30, 160, 162, 4096 + 6} {
+		input := make([]byte, n)
+		rng.Read(input)
+
+		want := make([]float64, n/2)
+		ambmLUTExecuteGo(lut, input, want)
+
+		got := make([]float64, n/2)
+		lut.Execute(input, got)
+
+		for idx := range want {
+			if math.Abs(got[idx]-want[idx]) > 1e-3 {
+				t.Fatalf("n=%d idx=%d: got %v, want %v", n, idx, got[idx], want[idx])
+			}
+		}
+	}
+}
+
+// TestMagLUTExecuteNonDefaultLUTFallsBack guards sameLUT's fallback: a
+// custom lut must never be run through a kernel whose constants were baked
+// in for the default table.
+func TestMagLUTExecuteNonDefaultLUTFallsBack(t *testing.T) {
+	lut := make(MagLUT, 0x100)
+	for idx := range lut {
+		lut[idx] = float64(idx) // deliberately not NewSqrtMagLUT's table
+	}
+
+	input := make([]byte, 32)
+	for idx := range input {
+		input[idx] = byte(idx)
+	}
+
+	want := make([]float64, len(input)/2)
+	magLUTExecuteGo(lut, input, want)
+
+	got := make([]float64, len(input)/2)
+	lut.Execute(input, got)
+
+	for idx := range want {
+		if got[idx] != want[idx] {
+			t.Fatalf("idx=%d: got %v, want %v (dispatch used a kernel tuned for a different lut)", idx, got[idx], want[idx])
+		}
+	}
+}
@@ -0,0 +1,188 @@
+// RTLAMR - An rtl-sdr receiver for smart meters operating in the 900MHz ISM band.
+// Copyright (C) 2015 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package decode
+
+import "math"
+
+// FilterType selects the matched filter kernel a Decoder uses to turn
+// demodulated magnitude samples into a bit-decision signal.
+type FilterType int
+
+const (
+	// ManchesterFilterType integrates-and-dumps over a pair of symbol
+	// lengths, matched to the odd symmetry of Manchester-coded signals.
+	// This is the zero-value, preserving prior behavior.
+	ManchesterFilterType FilterType = iota
+	// RaisedCosineFilterType matches against a raised-cosine pulse shaped
+	// to Cfg.SymbolLength2, suited to OOK/SCM+-like symbols.
+	RaisedCosineFilterType
+	// FIRFilterType convolves against the caller-supplied Cfg.FIRTaps,
+	// for protocols whose symbol shape isn't one of the above.
+	FIRFilterType
+)
+
+func (t FilterType) String() string {
+	switch t {
+	case ManchesterFilterType:
+		return "manchester"
+	case RaisedCosineFilterType:
+		return "raised-cosine"
+	case FIRFilterType:
+		return "fir"
+	default:
+		return "unknown"
+	}
+}
+
+// A Filter knows how to turn a block of demodulated magnitude samples into a
+// matched-filter output whose sign is the bit-decision for each sample.
+// Execute writes fewer output samples than it reads input samples, the
+// shortfall being set by the filter's span; see each implementation for the
+// exact relationship.
+type Filter interface {
+	Execute(input, output []float64)
+}
+
+// ManchesterFilter is the matched filter for Manchester coded signals.
+// Output signal's sign at each sample determines the bit-value since
+// Manchester symbols have odd symmetry.
+type ManchesterFilter struct {
+	symbolLength, symbolLength2 int
+	csum                        []float64
+}
+
+// NewManchesterFilter allocates a ManchesterFilter sized for cfg.
+func NewManchesterFilter(cfg PacketConfig) *ManchesterFilter {
+	return &ManchesterFilter{
+		symbolLength:  cfg.SymbolLength,
+		symbolLength2: cfg.SymbolLength2,
+		csum:          make([]float64, cfg.BlockSize+cfg.SymbolLength2+1),
+	}
+}
+
+func (f *ManchesterFilter) Execute(input, output []float64) {
+	// Computing the cumulative summation over the signal simplifies
+	// filtering to the difference of a pair of subtractions. The running
+	// sum carries a sequential dependency from one sample to the next, so
+	// it isn't a good fit for SIMD; the windowed combine below is.
+	var sum float64
+	for idx, v := range input {
+		sum += v
+		f.csum[idx+1] = sum
+	}
+
+	// Filter result is difference of summation of lower and upper symbols.
+	n := len(input) - f.symbolLength2
+	filterCombine(f.csum, f.symbolLength, f.symbolLength2, n, output)
+}
+
+// filterCombine computes output[idx] = (csum[idx+symbolLength] - csum[idx]) -
+// (csum[idx+symbolLength2] - csum[idx+symbolLength]) for idx in [0, n), the
+// windowed difference ManchesterFilter needs once the cumulative sum is
+// known. It defaults to the portable Go version and may be overridden in an
+// arch-specific init() with a vectorized implementation.
+var filterCombine = filterCombineGo
+
+func filterCombineGo(csum []float64, symbolLength, symbolLength2, n int, output []float64) {
+	lower := csum[symbolLength:]
+	upper := csum[symbolLength2:]
+	for idx := 0; idx < n; idx++ {
+		output[idx] = (lower[idx] - csum[idx]) - (upper[idx] - lower[idx])
+	}
+}
+
+// FIRFilter is a generic finite-impulse-response matched filter driven by a
+// caller-supplied set of coefficients. It has no structure to exploit beyond
+// direct convolution, so protocol-specific filters below build their taps
+// and embed it rather than reimplementing the convolution loop.
+type FIRFilter struct {
+	Taps []float64
+}
+
+// NewFIRFilter returns a FIRFilter that convolves against taps.
+func NewFIRFilter(taps []float64) *FIRFilter {
+	return &FIRFilter{Taps: taps}
+}
+
+func (f *FIRFilter) Execute(input, output []float64) {
+	// An empty tap set (PacketConfig{FilterType: FIRFilterType} with
+	// FIRTaps left unset, say) has no window to convolve against; write an
+	// all-zero output rather than letting len(input)-len(f.Taps)+1 run past
+	// the end of output below.
+	if len(f.Taps) == 0 {
+		for idx := range output {
+			output[idx] = 0
+		}
+		return
+	}
+
+	n := len(input) - len(f.Taps) + 1
+	for idx := 0; idx < n; idx++ {
+		var sum float64
+		for t, tap := range f.Taps {
+			sum += input[idx+t] * tap
+		}
+		output[idx] = sum
+	}
+}
+
+// RaisedCosineFilter matches against a raised-cosine pulse spanning one
+// symbol, suited to OOK/SCM+-like signals whose symbols don't have the odd
+// symmetry ManchesterFilter relies on.
+type RaisedCosineFilter struct {
+	FIRFilter
+	Beta float64
+}
+
+// NewRaisedCosineFilter builds a RaisedCosineFilter with taps spanning
+// cfg.SymbolLength2 samples and the given roll-off factor beta.
+func NewRaisedCosineFilter(cfg PacketConfig, beta float64) *RaisedCosineFilter {
+	taps := raisedCosineTaps(cfg.SymbolLength2, beta)
+	return &RaisedCosineFilter{FIRFilter: FIRFilter{Taps: taps}, Beta: beta}
+}
+
+func raisedCosineTaps(n int, beta float64) []float64 {
+	taps := make([]float64, n)
+	center := float64(n-1) / 2
+
+	for idx := range taps {
+		t := float64(idx) - center
+		x := t / float64(n)
+
+		switch {
+		case t == 0:
+			taps[idx] = 1
+		case beta != 0 && math.Abs(math.Abs(2*beta*x)-1) < 1e-9:
+			// The general formula below has a removable singularity here
+			// (both numerator and denominator go to 0); an exact ==1
+			// comparison essentially never hits it for real
+			// (SymbolLength2, beta) pairs, so use an epsilon instead.
+			taps[idx] = (math.Pi / 4) * sinc(x)
+		default:
+			taps[idx] = sinc(x) * math.Cos(math.Pi*beta*x) / (1 - math.Pow(2*beta*x, 2))
+		}
+	}
+
+	return taps
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
@@ -0,0 +1,45 @@
+// RTLAMR - An rtl-sdr receiver for smart meters operating in the 900MHz ISM band.
+// Copyright (C) 2015 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build amd64 && gc && !purego
+
+package decode
+
+func init() {
+	if hasAVX2 {
+		filterCombine = filterCombineAVX2
+	}
+}
+
+// filterCombineCoreAVX2 computes out[idx] = 2*b[idx] - a[idx] - c[idx] for
+// groups of 4 float64 at a time; see filter_amd64.s. The group count is
+// taken from len(a), so b, c and out must all be at least len(a) long.
+//
+//go:noescape
+func filterCombineCoreAVX2(a, b, c, out []float64)
+
+func filterCombineAVX2(csum []float64, symbolLength, symbolLength2, n int, output []float64) {
+	groups := n / 4
+	if groups > 0 {
+		g4 := groups * 4
+		filterCombineCoreAVX2(csum[:g4], csum[symbolLength:][:g4], csum[symbolLength2:][:g4], output[:g4])
+	}
+
+	if rem := n - groups*4; rem > 0 {
+		off := groups * 4
+		filterCombineGo(csum[off:], symbolLength, symbolLength2, rem, output[off:])
+	}
+}
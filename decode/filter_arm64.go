@@ -0,0 +1,43 @@
+// RTLAMR - An rtl-sdr receiver for smart meters operating in the 900MHz ISM band.
+// Copyright (C) 2015 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build arm64 && gc && !purego
+
+package decode
+
+func init() {
+	filterCombine = filterCombineNEON
+}
+
+// filterCombineCoreNEON computes out[idx] = 2*b[idx] - a[idx] - c[idx] for
+// groups of 2 float64 at a time; see filter_arm64.s. The group count is
+// taken from len(a), so b, c and out must all be at least len(a) long.
+//
+//go:noescape
+func filterCombineCoreNEON(a, b, c, out []float64)
+
+func filterCombineNEON(csum []float64, symbolLength, symbolLength2, n int, output []float64) {
+	groups := n / 2
+	if groups > 0 {
+		g2 := groups * 2
+		filterCombineCoreNEON(csum[:g2], csum[symbolLength:][:g2], csum[symbolLength2:][:g2], output[:g2])
+	}
+
+	if rem := n - groups*2; rem > 0 {
+		off := groups * 2
+		filterCombineGo(csum[off:], symbolLength, symbolLength2, rem, output[off:])
+	}
+}
@@ -0,0 +1,116 @@
+// RTLAMR - An rtl-sdr receiver for smart meters operating in the 900MHz ISM band.
+// Copyright (C) 2015 Douglas Hall
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package decode
+
+import (
+	"math"
+	"testing"
+)
+
+// TestFIRFilterEmptyTaps guards against the index-out-of-range panic an
+// empty Taps slice used to cause: n := len(input)-len(f.Taps)+1 ran one
+// past the end of output.
+func TestFIRFilterEmptyTaps(t *testing.T) {
+	f := NewFIRFilter(nil)
+	input := make([]float64, 10)
+	output := make([]float64, 10)
+	f.Execute(input, output)
+	for idx, v := range output {
+		if v != 0 {
+			t.Fatalf("output[%d] = %v, want 0", idx, v)
+		}
+	}
+}
+
+func TestFIRFilterConvolution(t *testing.T) {
+	f := NewFIRFilter([]float64{1, -1})
+	input := []float64{1, 2, 4, 7, 11}
+	output := make([]float64, len(input)-len(f.Taps)+1)
+	f.Execute(input, output)
+
+	want := []float64{-1, -2, -3, -4}
+	for idx := range want {
+		if output[idx] != want[idx] {
+			t.Fatalf("output[%d] = %v, want %v", idx, output[idx], want[idx])
+		}
+	}
+}
+
+func TestManchesterFilter(t *testing.T) {
+	cfg := benchmarkConfig()
+	f := NewManchesterFilter(cfg)
+
+	input := make([]float64, cfg.BlockSize+cfg.SymbolLength2)
+	for idx := range input {
+		// A Manchester-coded "1" bit: high for the first half of each
+		// symbol, low for the second half.
+		if idx%cfg.SymbolLength2 < cfg.SymbolLength {
+			input[idx] = 1
+		} else {
+			input[idx] = -1
+		}
+	}
+
+	output := make([]float64, len(input)-cfg.SymbolLength2)
+	f.Execute(input, output)
+
+	// The first full symbol pair should register a strong, non-zero
+	// response; this is a smoke test, not an exact-value check, since the
+	// AVX2/NEON fast paths may back filterCombine on this platform.
+	if output[0] == 0 {
+		t.Fatalf("output[0] = 0, want non-zero response to alternating symbols")
+	}
+}
+
+// TestRaisedCosineFilterTapsSingularity picks a beta that lands exactly on
+// the raised-cosine pole (|2*beta*x| == 1) for one tap and checks it takes
+// the special-case branch rather than falling through to the general
+// formula's near-0/near-0 division, which is unreliable at floating-point
+// precision right at the pole.
+func TestRaisedCosineFilterTapsSingularity(t *testing.T) {
+	const n = 8
+	const idx = 6
+
+	center := float64(n-1) / 2
+	x := (float64(idx) - center) / n
+	beta := 1 / (2 * x)
+
+	taps := raisedCosineTaps(n, beta)
+
+	want := (math.Pi / 4) * sinc(x)
+	if got := taps[idx]; math.Abs(got-want) > 1e-9 {
+		t.Fatalf("taps[%d] = %v, want %v (special-case branch not taken at the pole)", idx, got, want)
+	}
+}
+
+func TestRaisedCosineFilterTapsSymmetric(t *testing.T) {
+	cfg := benchmarkConfig()
+	f := NewRaisedCosineFilter(cfg, 0.5)
+
+	if len(f.Taps) != cfg.SymbolLength2 {
+		t.Fatalf("len(Taps) = %d, want %d", len(f.Taps), cfg.SymbolLength2)
+	}
+
+	// A raised-cosine pulse is symmetric about its center.
+	n := len(f.Taps)
+	for i := 0; i < n/2; i++ {
+		got, want := f.Taps[i], f.Taps[n-1-i]
+		if math.Abs(got-want) > 1e-9 {
+			t.Fatalf("Taps[%d] = %v, Taps[%d] = %v, want symmetric", i, got, n-1-i, want)
+		}
+	}
+}